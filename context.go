@@ -0,0 +1,162 @@
+// Copyright © 2019 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wallet
+
+import (
+	"context"
+	"errors"
+
+	types "github.com/wealdtech/go-eth2-wallet-types"
+)
+
+// ErrWalletsPartial is sent on the error channel returned by WalletsContext when
+// iteration stops before the store has been fully scanned because the context was
+// cancelled. A wallet whose stored data cannot be decoded is skipped without any
+// error being sent. Callers can check for this to tell a cancelled iteration apart
+// from one that ran to completion.
+var ErrWalletsPartial = errors.New("wallet iteration did not complete")
+
+// ContextStore is an optional extension to types.Store for backends that can
+// honour a context when retrieving wallets, for example to cancel in-flight
+// requests against S3, GCS or a distributed store. Stores that do not implement
+// it fall back to their non-context equivalents.
+type ContextStore interface {
+	types.Store
+
+	// RetrieveWalletContext is the context-aware equivalent of RetrieveWallet.
+	RetrieveWalletContext(ctx context.Context, name string) ([]byte, error)
+
+	// RetrieveWalletsContext is the context-aware equivalent of RetrieveWallets.
+	RetrieveWalletsContext(ctx context.Context) <-chan []byte
+}
+
+// retrieveWallet fetches the raw bytes for a named wallet, using the context-aware
+// path if the store supports it.
+func retrieveWallet(ctx context.Context, s types.Store, name string) ([]byte, error) {
+	if cs, ok := s.(ContextStore); ok {
+		return cs.RetrieveWalletContext(ctx, name)
+	}
+	return s.RetrieveWallet(name)
+}
+
+// retrieveWallets fetches the raw bytes of every wallet in the store, using the
+// context-aware path if the store supports it.
+func retrieveWallets(ctx context.Context, s types.Store) <-chan []byte {
+	if cs, ok := s.(ContextStore); ok {
+		return cs.RetrieveWalletsContext(ctx)
+	}
+	return s.RetrieveWallets()
+}
+
+// WalletsContext is the context-aware equivalent of Wallets. It returns a channel
+// of wallets and a second channel on which a single error is sent if iteration
+// stops early, for example because ctx was cancelled; the error channel is closed
+// without a value if iteration completes normally.
+func WalletsContext(ctx context.Context) (<-chan types.Wallet, <-chan error) {
+	return walletsFromStore(ctx, store)
+}
+
+// walletsFromStore does the work of WalletsContext against an explicit store,
+// so that the cancellation and draining behaviour can be tested against a
+// fake store rather than only the package-global one.
+//
+// If s is not a ContextStore, the underlying RetrieveWallets is not itself
+// cancellable: its producing goroutine has no way to learn that this iteration
+// stopped early. To avoid leaking it blocked on a send nobody will read,
+// walletsFromStore drains the rest of that channel in the background once ctx
+// is cancelled, rather than abandoning it.
+func walletsFromStore(ctx context.Context, s types.Store) (<-chan types.Wallet, <-chan error) {
+	ch := make(chan types.Wallet, 1024)
+	errCh := make(chan error, 1)
+	_, contextAware := s.(ContextStore)
+	src := retrieveWallets(ctx, s)
+	go func() {
+		defer close(ch)
+		defer close(errCh)
+		for {
+			var data []byte
+			var ok bool
+			select {
+			case data, ok = <-src:
+				if !ok {
+					return
+				}
+			case <-ctx.Done():
+				errCh <- ErrWalletsPartial
+				if !contextAware {
+					go drainWallets(src)
+				}
+				return
+			}
+			wallet, err := walletFromBytes(data)
+			if err == nil {
+				select {
+				case ch <- wallet:
+				case <-ctx.Done():
+					errCh <- ErrWalletsPartial
+					if !contextAware {
+						go drainWallets(src)
+					}
+					return
+				}
+			}
+		}
+	}()
+	return ch, errCh
+}
+
+// drainWallets reads src to completion without doing anything with the
+// results, letting a non-context-aware store's RetrieveWallets goroutine
+// finish sending and exit instead of blocking forever on an abandoned channel.
+func drainWallets(src <-chan []byte) {
+	for range src {
+	}
+}
+
+// OpenWalletContext is the context-aware equivalent of OpenWallet.
+func OpenWalletContext(ctx context.Context, name string, opts ...Option) (types.Wallet, error) {
+	options := walletOptions{
+		store:     store,
+		encryptor: encryptor,
+	}
+	for _, o := range opts {
+		o.apply(&options)
+	}
+
+	data, err := retrieveWallet(ctx, options.store, name)
+	if err != nil {
+		return nil, err
+	}
+	return walletFromBytes(data)
+}
+
+// CreateWalletContext is the context-aware equivalent of CreateWallet. The
+// underlying wallet types do not yet accept a context of their own, so this
+// checks ctx before starting work and is otherwise equivalent to CreateWallet.
+func CreateWalletContext(ctx context.Context, name string, opts ...Option) (types.Wallet, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return CreateWallet(name, opts...)
+}
+
+// ImportWalletContext is the context-aware equivalent of ImportWallet. The
+// underlying wallet types do not yet accept a context of their own, so this
+// checks ctx before starting work and is otherwise equivalent to ImportWallet.
+func ImportWalletContext(ctx context.Context, encryptedData []byte, passphrase []byte) (types.Wallet, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return ImportWallet(encryptedData, passphrase)
+}
@@ -0,0 +1,85 @@
+// Copyright © 2019 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wallet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// fakeStore is a minimal, non-context-aware types.Store whose RetrieveWallets
+// keeps sending on its channel for as long as fed, exactly like a real
+// blocking-I/O backend would, letting tests observe whether a cancelled
+// consumer leaves that goroutine stuck.
+type fakeStore struct {
+	wallets chan []byte
+}
+
+func (s *fakeStore) RetrieveWallet(name string) ([]byte, error) { return nil, nil }
+func (s *fakeStore) RetrieveWallets() <-chan []byte             { return s.wallets }
+func (s *fakeStore) StoreWallet(id uuid.UUID, name string, data []byte) error {
+	return nil
+}
+func (s *fakeStore) RetrieveAccounts(walletID uuid.UUID) <-chan []byte {
+	ch := make(chan []byte)
+	close(ch)
+	return ch
+}
+func (s *fakeStore) StoreAccount(walletID uuid.UUID, accountID uuid.UUID, data []byte) error {
+	return nil
+}
+
+// unknownTypeWallet is valid JSON for walletFromBytes's initial decode but of
+// an unrecognised wallet type, so it is rejected without touching the
+// package-global store/encryptor used by the real nd/hd/distributed decoders.
+const unknownTypeWallet = `{"uuid":"3e762a64-0301-4c11-8fa8-0f97cf31d0e5","name":"w","type":"unknown"}`
+
+func TestWalletsFromStoreDrainsAfterCancellation(t *testing.T) {
+	s := &fakeStore{wallets: make(chan []byte)}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	_, errCh := walletsFromStore(ctx, s)
+
+	// Send one item, then cancel: the consumer goroutine should report
+	// ErrWalletsPartial and stop reading from the wallet channel itself, but
+	// a drain goroutine must keep accepting sends so fakeStore never blocks.
+	s.wallets <- []byte(unknownTypeWallet)
+	cancel()
+
+	select {
+	case err := <-errCh:
+		if err != ErrWalletsPartial {
+			t.Fatalf("expected ErrWalletsPartial, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for ErrWalletsPartial")
+	}
+
+	// If walletsFromStore failed to start draining, this send blocks forever
+	// and the test times out -- proving the producer goroutine would have
+	// leaked in the fix this guards against.
+	sent := make(chan struct{})
+	go func() {
+		s.wallets <- []byte(unknownTypeWallet)
+		close(sent)
+	}()
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("send after cancellation blocked: drain goroutine did not start")
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright © 2019 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wallet
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	types "github.com/wealdtech/go-eth2-wallet-types"
+	distributed "github.com/wealdtech/go-eth2-wallet/distributed"
+)
+
+// privateKeyExporter is an optional extension to types.Account for wallet types
+// (for example hd) whose unlocked accounts can reveal their private key, needed
+// to split it when converting to a distributed wallet.
+type privateKeyExporter interface {
+	types.Account
+
+	// PrivateKey returns the account's private key. The account must be unlocked.
+	PrivateKey() (e2types.PrivateKey, error)
+}
+
+// ConvertWallet re-homes every account in src into a freshly-created wallet of type
+// dstType, preserving account names and public keys; account UUIDs are also
+// preserved for "hd" to "distributed" (every participant's own copy of the
+// account keeps src's UUID), but not for "distributed" to "nd", since
+// types.WalletAccountImporter.ImportAccount has no way to specify one. Accounts
+// are streamed lazily from src's iterator, so memory usage is O(1) in the
+// number of accounts. The destination wallet is created with CreateWallet under
+// WithWalletName, which defaults to "<src.Name()>-<dstType>" since the
+// destination cannot share the source's name in the same store; ConvertWallet
+// refuses to overwrite an existing wallet of that name.
+//
+// Two conversions are supported: "hd" to "distributed", which Shamir-splits each
+// account's private key into the shares configured by WithPeers/WithSigningThreshold,
+// keeps this node's own share, and writes every other participant's share to its
+// own sub-store via WithPeerStores, and "distributed" to "nd", which reconstructs
+// the composite private key from the account's shares (see
+// distributed.Account.Reconstruct) and stores it as a plain non-deterministic account.
+func ConvertWallet(src types.Wallet, dstType string, opts ...Option) (types.Wallet, error) {
+	options := walletOptions{
+		store:     store,
+		encryptor: encryptor,
+	}
+	for _, o := range opts {
+		o.apply(&options)
+	}
+
+	dstName := defaultDestinationName(options.walletName, src.Name(), dstType)
+
+	dst, err := CreateWallet(dstName, append(opts, WithType(dstType), WithWalletName(dstName))...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create destination wallet %q: %v", dstName, err)
+	}
+
+	failures := make([]string, 0)
+	for account := range src.Accounts() {
+		if err := convertAccount(src.Type(), dstType, dst, account, options); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", account.Name(), err))
+		}
+	}
+	if len(failures) > 0 {
+		return dst, fmt.Errorf("failed to convert %d account(s): %s", len(failures), strings.Join(failures, "; "))
+	}
+	return dst, nil
+}
+
+// defaultDestinationName returns walletName if set, otherwise a name for the
+// destination wallet that cannot collide with srcName in the same store.
+func defaultDestinationName(walletName string, srcName string, dstType string) string {
+	if walletName != "" {
+		return walletName
+	}
+	return fmt.Sprintf("%s-%s", srcName, dstType)
+}
+
+// convertAccount re-creates a single account from src in dst, applying whatever
+// key transformation the src type to dst type conversion requires.
+func convertAccount(srcType string, dstType string, dst types.Wallet, account types.Account, options walletOptions) error {
+	switch {
+	case srcType == "hd" && dstType == "distributed":
+		return splitToDistributed(dst, account, options)
+	case srcType == "distributed" && dstType == "nd":
+		return reconstructToND(dst, account, options)
+	default:
+		return fmt.Errorf("unsupported conversion from %q to %q", srcType, dstType)
+	}
+}
+
+// splitToDistributed Shamir-splits account's private key into the shares
+// described by options.peers/options.signingThreshold, keeping this node's own
+// share (identified by the participant registered with an empty endpoint) in
+// dst, and writing every other participant's share to its own sub-store via
+// writePeerShares.
+func splitToDistributed(dst types.Wallet, account types.Account, options walletOptions) error {
+	exporter, ok := account.(privateKeyExporter)
+	if !ok {
+		return fmt.Errorf("account does not expose its private key for splitting")
+	}
+	privateKey, err := exporter.PrivateKey()
+	if err != nil {
+		return err
+	}
+
+	localID, err := distributed.LocalParticipant(options.peers)
+	if err != nil {
+		return err
+	}
+
+	shares, vector, err := distributed.Split(privateKey, options.signingThreshold, options.peers)
+	if err != nil {
+		return err
+	}
+
+	distDst, ok := dst.(*distributed.Wallet)
+	if !ok {
+		return fmt.Errorf("destination wallet is not a distributed wallet")
+	}
+	_, err = distDst.CreateDistributedAccount(account.ID(), account.Name(), localID, shares[localID].Value, vector, options.peers, options.signingThreshold, options.passphrase)
+	if err != nil {
+		return err
+	}
+
+	return writePeerShares(account.ID(), account.Name(), shares, vector, options, localID)
+}
+
+// writePeerShares writes shares[id] for every participant other than localID
+// that has a sub-store configured in options.peerStores -- the DKC split flow
+// by which remote participants actually obtain their share of a freshly split
+// key. A participant with no configured sub-store is skipped: it is expected
+// to be provisioned out of band (or is not reachable from this node at all).
+func writePeerShares(id uuid.UUID, name string, shares map[uint64]*distributed.Share, vector distributed.VerificationVector, options walletOptions, localID uint64) error {
+	failures := make([]string, 0)
+	for peerID := range options.peers {
+		if peerID == localID {
+			continue
+		}
+		peerStore, ok := options.peerStores[peerID]
+		if !ok {
+			continue
+		}
+		peerWallet, err := openOrCreateDistributedWallet(name, peerStore, options.encryptor)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("participant %d: %v", peerID, err))
+			continue
+		}
+		if _, err := peerWallet.CreateDistributedAccount(id, name, peerID, shares[peerID].Value, vector, options.peers, options.signingThreshold, options.passphrase); err != nil {
+			failures = append(failures, fmt.Sprintf("participant %d: %v", peerID, err))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to write %d peer share(s): %s", len(failures), strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// openOrCreateDistributedWallet returns the distributed wallet named name in
+// store, creating it if it does not already exist, so writePeerShares can be
+// called repeatedly (for example once per converted account) against the same
+// per-peer sub-store.
+func openOrCreateDistributedWallet(name string, peerStore types.Store, peerEncryptor types.Encryptor) (*distributed.Wallet, error) {
+	if data, err := peerStore.RetrieveWallet(name); err == nil {
+		wallet, err := distributed.DeserializeWallet(data, peerStore, peerEncryptor)
+		if err != nil {
+			return nil, err
+		}
+		return wallet.(*distributed.Wallet), nil
+	}
+
+	wallet, err := distributed.CreateWallet(name, peerStore, peerEncryptor)
+	if err != nil {
+		return nil, err
+	}
+	return wallet.(*distributed.Wallet), nil
+}
+
+// reconstructToND recovers account's composite private key from its shares and
+// stores it as a plain account in dst.
+func reconstructToND(dst types.Wallet, account types.Account, options walletOptions) error {
+	distAccount, ok := account.(*distributed.Account)
+	if !ok {
+		return fmt.Errorf("account is not a distributed account")
+	}
+	privateKey, err := distAccount.Reconstruct(options.passphrase)
+	if err != nil {
+		return err
+	}
+
+	importer, ok := dst.(types.WalletAccountImporter)
+	if !ok {
+		return fmt.Errorf("destination wallet cannot import accounts with existing keys")
+	}
+	_, err = importer.ImportAccount(account.Name(), privateKey.Marshal(), options.passphrase)
+	return err
+}
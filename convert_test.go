@@ -0,0 +1,52 @@
+// Copyright © 2019 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wallet
+
+import "testing"
+
+func TestDefaultDestinationName(t *testing.T) {
+	tests := []struct {
+		name       string
+		walletName string
+		srcName    string
+		dstType    string
+		want       string
+	}{
+		{
+			name:    "NoOverride",
+			srcName: "primary",
+			dstType: "distributed",
+			want:    "primary-distributed",
+		},
+		{
+			name:       "ExplicitOverride",
+			walletName: "primary-backup",
+			srcName:    "primary",
+			dstType:    "distributed",
+			want:       "primary-backup",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := defaultDestinationName(test.walletName, test.srcName, test.dstType)
+			if got != test.want {
+				t.Errorf("expected %q, got %q", test.want, got)
+			}
+			if got == test.srcName {
+				t.Errorf("destination name %q must not collide with source name", got)
+			}
+		})
+	}
+}
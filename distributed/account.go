@@ -0,0 +1,302 @@
+// Copyright © 2019 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distributed
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	bls12381 "github.com/kilic/bls12-381"
+
+	"github.com/google/uuid"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	types "github.com/wealdtech/go-eth2-wallet-types"
+)
+
+// PeerClient requests a partial signature over data for the named account from a
+// single peer endpoint. The default used by Sign speaks a plain HTTP JSON API;
+// deployments with their own peer protocol can install one via SetPeerClient.
+type PeerClient interface {
+	RequestPartialSignature(ctx context.Context, endpoint string, accountID uuid.UUID, data []byte) (e2types.Signature, error)
+}
+
+var peerClient PeerClient = &httpPeerClient{}
+
+// SetPeerClient overrides the PeerClient used to gather partial signatures from
+// peers, for deployments whose peers speak a protocol other than the default
+// HTTP JSON API.
+func SetPeerClient(client PeerClient) {
+	peerClient = client
+}
+
+// Account is an account held by a distributed wallet. Sign never reconstructs its
+// private key locally: it gathers partial signatures from the account's peers
+// and recombines them by Lagrange interpolation in the exponent once at least
+// SigningThreshold of them have responded. Only Reconstruct rebuilds the full
+// private key.
+type Account struct {
+	id                 uuid.UUID
+	name               string
+	wallet             *Wallet
+	compositePublicKey e2types.PublicKey
+	verificationVector VerificationVector
+	participants       map[uint64]string
+	signingThreshold   uint32
+	localID            uint64
+	encryptedShare     []byte
+	unlocked           bool
+	share              *big.Int
+}
+
+type accountJSON struct {
+	ID                 uuid.UUID         `json:"uuid"`
+	Name               string            `json:"name"`
+	CompositePublicKey string            `json:"composite_public_key"`
+	VerificationVector []string          `json:"verification_vector"`
+	Participants       map[uint64]string `json:"participants"`
+	SigningThreshold   uint32            `json:"signing_threshold"`
+	LocalParticipantID uint64            `json:"local_participant_id"`
+	Share              string            `json:"share,omitempty"`
+}
+
+// ID provides the ID for the account.
+func (a *Account) ID() uuid.UUID { return a.id }
+
+// Name provides the name for the account.
+func (a *Account) Name() string { return a.name }
+
+// PublicKey provides the public key for the account, which for a distributed
+// account is the composite public key of the group.
+func (a *Account) PublicKey() e2types.PublicKey { return a.compositePublicKey }
+
+// Wallet provides the wallet that holds the account.
+func (a *Account) Wallet() types.Wallet { return a.wallet }
+
+// CompositePublicKey is the public key of the group: the first entry of the
+// account's verification vector, which commits to the split secret.
+func (a *Account) CompositePublicKey() e2types.PublicKey { return a.compositePublicKey }
+
+// Participants is the set of peer endpoints holding a share of this account's key, keyed by participant ID.
+func (a *Account) Participants() map[uint64]string { return a.participants }
+
+// SigningThreshold is the minimum number of partial signatures required to reconstruct a group signature.
+func (a *Account) SigningThreshold() uint32 { return a.signingThreshold }
+
+// IsUnlocked reports if the account is unlocked. A distributed account in
+// "verify-only" mode (no local share, e.g. opened without peer contact) can never
+// be unlocked since it has no share of its own; Sign can still gather partial
+// signatures from its peers.
+func (a *Account) IsUnlocked() bool { return a.unlocked }
+
+// Unlock unlocks the account's local share ready for it to contribute a partial
+// signature during Sign.
+func (a *Account) Unlock(passphrase []byte) error {
+	if len(a.encryptedShare) == 0 {
+		return fmt.Errorf("account %q has no local share to unlock", a.name)
+	}
+	data, err := a.wallet.encryptor.Decrypt(a.encryptedShare, string(passphrase))
+	if err != nil {
+		return fmt.Errorf("incorrect passphrase")
+	}
+	a.share = new(big.Int).SetBytes(data)
+	a.unlocked = true
+	return nil
+}
+
+// Lock locks the account, discarding its decrypted local share.
+func (a *Account) Lock() error {
+	a.share = nil
+	a.unlocked = false
+	return nil
+}
+
+// Sign generates a group signature over data. It requests a partial signature from
+// each of the account's peers (in addition to its own, if unlocked) and, once at
+// least SigningThreshold have responded, recombines them in the exponent via
+// Lagrange interpolation to recover the signature the composite private key would
+// have produced, without ever reconstructing that key.
+func (a *Account) Sign(data []byte) (e2types.Signature, error) {
+	ctx := context.Background()
+	partials := make(map[uint64]e2types.Signature)
+
+	if a.unlocked {
+		sig, err := signWithShare(a.share, data)
+		if err == nil {
+			partials[a.localID] = sig
+		}
+	}
+
+	for id, endpoint := range a.participants {
+		if id == a.localID {
+			continue
+		}
+		if _, ok := partials[id]; ok {
+			continue
+		}
+		sig, err := peerClient.RequestPartialSignature(ctx, endpoint, a.id, data)
+		if err != nil {
+			continue
+		}
+		partials[id] = sig
+		if uint32(len(partials)) >= a.signingThreshold {
+			break
+		}
+	}
+
+	if uint32(len(partials)) < a.signingThreshold {
+		return nil, fmt.Errorf("received %d of %d required partial signatures", len(partials), a.signingThreshold)
+	}
+
+	return combinePartialSignatures(partials)
+}
+
+// signWithShare produces a partial signature over data using a local private key share.
+func signWithShare(share *big.Int, data []byte) (e2types.Signature, error) {
+	key, err := e2types.BLSPrivateKeyFromBytes(leftPad32(share.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	return key.Sign(data)
+}
+
+// combinePartialSignatures reconstructs the group signature from a set of partial
+// signatures, keyed by participant ID, by computing each participant's Lagrange
+// coefficient and combining the signatures (BLS12-381 G2 points) in the exponent:
+// groupSig = sum_i(lambda_i * partialSig_i).
+func combinePartialSignatures(partials map[uint64]e2types.Signature) (e2types.Signature, error) {
+	participants := make([]uint64, 0, len(partials))
+	for id := range partials {
+		participants = append(participants, id)
+	}
+
+	g2 := bls12381.NewG2()
+	result := bls12381.PointG2{}
+
+	for id, sig := range partials {
+		point, err := g2.FromCompressed(sig.Marshal())
+		if err != nil {
+			return nil, fmt.Errorf("invalid partial signature from participant %d: %v", id, err)
+		}
+		weighted := bls12381.PointG2{}
+		g2.MulScalar(&weighted, point, lagrangeCoefficient(participants, id))
+		g2.Add(&result, &result, &weighted)
+	}
+
+	return e2types.BLSSignatureFromBytes(g2.ToCompressed(&result))
+}
+
+// Reconstruct recovers the account's composite private key by gathering raw
+// shares -- its own, always unlocked with passphrase, plus one from each peer in
+// turn -- until at least SigningThreshold have been collected, then combining
+// them by Lagrange interpolation. Unlike Sign, this exposes the full private
+// key, so it should only be used for an explicit, operator-authorised recovery,
+// such as converting a distributed account back to a plain "nd" account.
+func (a *Account) Reconstruct(passphrase []byte) (e2types.PrivateKey, error) {
+	if err := a.Unlock(passphrase); err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	shares := map[uint64]*big.Int{a.localID: a.share}
+
+	for id, endpoint := range a.participants {
+		if id == a.localID {
+			continue
+		}
+		if _, ok := shares[id]; ok {
+			continue
+		}
+		share, err := shareClient.RequestShare(ctx, endpoint, a.id)
+		if err != nil {
+			continue
+		}
+		shares[id] = share
+		if uint32(len(shares)) >= a.signingThreshold {
+			break
+		}
+	}
+
+	if uint32(len(shares)) < a.signingThreshold {
+		return nil, fmt.Errorf("collected %d of %d required shares", len(shares), a.signingThreshold)
+	}
+
+	return e2types.BLSPrivateKeyFromBytes(leftPad32(reconstructSecret(shares).Bytes()))
+}
+
+func (a *Account) toJSON() *accountJSON {
+	vector := make([]string, len(a.verificationVector))
+	for i, c := range a.verificationVector {
+		vector[i] = hex.EncodeToString(c)
+	}
+	j := &accountJSON{
+		ID:                 a.id,
+		Name:               a.name,
+		CompositePublicKey: hex.EncodeToString(a.compositePublicKey.Marshal()),
+		VerificationVector: vector,
+		Participants:       a.participants,
+		SigningThreshold:   a.signingThreshold,
+		LocalParticipantID: a.localID,
+	}
+	if len(a.encryptedShare) > 0 {
+		j.Share = hex.EncodeToString(a.encryptedShare)
+	}
+	return j
+}
+
+func deserializeAccount(wallet *Wallet, data []byte) (*Account, error) {
+	j := &accountJSON{}
+	if err := json.Unmarshal(data, j); err != nil {
+		return nil, err
+	}
+
+	vector := make(VerificationVector, len(j.VerificationVector))
+	for i, hexCommitment := range j.VerificationVector {
+		commitment, err := hex.DecodeString(hexCommitment)
+		if err != nil {
+			return nil, err
+		}
+		vector[i] = commitment
+	}
+
+	compositePublicKeyBytes, err := hex.DecodeString(j.CompositePublicKey)
+	if err != nil {
+		return nil, err
+	}
+	compositePublicKey, err := e2types.BLSPublicKeyFromBytes(compositePublicKeyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	account := &Account{
+		id:                 j.ID,
+		name:               j.Name,
+		wallet:             wallet,
+		compositePublicKey: compositePublicKey,
+		verificationVector: vector,
+		participants:       j.Participants,
+		signingThreshold:   j.SigningThreshold,
+		localID:            j.LocalParticipantID,
+	}
+	if j.Share != "" {
+		encryptedShare, err := hex.DecodeString(j.Share)
+		if err != nil {
+			return nil, err
+		}
+		account.encryptedShare = encryptedShare
+	}
+	return account, nil
+}
@@ -0,0 +1,180 @@
+// Copyright © 2019 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distributed
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+)
+
+// fakeStore is a minimal in-memory types.Store, just enough for Wallet and
+// Account to round-trip through CreateWallet/CreateDistributedAccount.
+type fakeStore struct {
+	wallets  map[string][]byte
+	accounts map[uuid.UUID]map[uuid.UUID][]byte
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		wallets:  make(map[string][]byte),
+		accounts: make(map[uuid.UUID]map[uuid.UUID][]byte),
+	}
+}
+
+func (s *fakeStore) RetrieveWallet(name string) ([]byte, error) {
+	data, ok := s.wallets[name]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return data, nil
+}
+
+func (s *fakeStore) RetrieveWallets() <-chan []byte {
+	ch := make(chan []byte, len(s.wallets))
+	for _, data := range s.wallets {
+		ch <- data
+	}
+	close(ch)
+	return ch
+}
+
+func (s *fakeStore) StoreWallet(id uuid.UUID, name string, data []byte) error {
+	s.wallets[name] = data
+	return nil
+}
+
+func (s *fakeStore) RetrieveAccounts(walletID uuid.UUID) <-chan []byte {
+	accs := s.accounts[walletID]
+	ch := make(chan []byte, len(accs))
+	for _, data := range accs {
+		ch <- data
+	}
+	close(ch)
+	return ch
+}
+
+func (s *fakeStore) StoreAccount(walletID uuid.UUID, accountID uuid.UUID, data []byte) error {
+	if s.accounts[walletID] == nil {
+		s.accounts[walletID] = make(map[uuid.UUID][]byte)
+	}
+	s.accounts[walletID][accountID] = data
+	return nil
+}
+
+// fakeEncryptor is a no-op types.Encryptor: it exists only so Account.Unlock's
+// decryption round-trips, not to test any real encryption scheme.
+type fakeEncryptor struct{}
+
+func (fakeEncryptor) Encrypt(data []byte, passphrase string) ([]byte, error) { return data, nil }
+func (fakeEncryptor) Decrypt(data []byte, passphrase string) ([]byte, error) { return data, nil }
+
+// TestSplitCreateReconstructRoundTrip exercises the path ConvertWallet relies
+// on: splitting a private key into shares, creating a distributed account from
+// the local share, and reconstructing the original private key from it. With a
+// signing threshold of 1 and a single, local-only participant, Reconstruct
+// needs no peer network, so the whole path runs deterministically in-process.
+func TestSplitCreateReconstructRoundTrip(t *testing.T) {
+	secretBytes := make([]byte, 32)
+	secretBytes[31] = 42
+	privateKey, err := e2types.BLSPrivateKeyFromBytes(secretBytes)
+	if err != nil {
+		t.Fatalf("unexpected error deriving private key: %v", err)
+	}
+
+	participants := map[uint64]string{1: ""}
+	shares, vector, err := Split(privateKey, 1, participants)
+	if err != nil {
+		t.Fatalf("unexpected error splitting key: %v", err)
+	}
+
+	store := newFakeStore()
+	walletIface, err := CreateWallet("w1", store, fakeEncryptor{})
+	if err != nil {
+		t.Fatalf("unexpected error creating wallet: %v", err)
+	}
+	wallet := walletIface.(*Wallet)
+
+	accountIface, err := wallet.CreateDistributedAccount(uuid.New(), "acc1", 1, shares[1].Value, vector, participants, 1, []byte("pass"))
+	if err != nil {
+		t.Fatalf("unexpected error creating distributed account: %v", err)
+	}
+	account := accountIface.(*Account)
+
+	if !bytes.Equal(account.CompositePublicKey().Marshal(), privateKey.PublicKey().Marshal()) {
+		t.Errorf("composite public key does not match the original private key's public key")
+	}
+
+	recovered, err := account.Reconstruct([]byte("pass"))
+	if err != nil {
+		t.Fatalf("unexpected error reconstructing private key: %v", err)
+	}
+	if !bytes.Equal(recovered.Marshal(), privateKey.Marshal()) {
+		t.Errorf("reconstructed private key does not match the original")
+	}
+}
+
+// TestReconstructWrongPassphrase confirms a wrong passphrase is rejected
+// rather than silently reconstructing garbage.
+func TestReconstructWrongPassphrase(t *testing.T) {
+	secretBytes := make([]byte, 32)
+	secretBytes[31] = 7
+	privateKey, err := e2types.BLSPrivateKeyFromBytes(secretBytes)
+	if err != nil {
+		t.Fatalf("unexpected error deriving private key: %v", err)
+	}
+
+	participants := map[uint64]string{1: ""}
+	shares, vector, err := Split(privateKey, 1, participants)
+	if err != nil {
+		t.Fatalf("unexpected error splitting key: %v", err)
+	}
+
+	store := newFakeStore()
+	walletIface, err := CreateWallet("w1", store, realisticEncryptor{})
+	if err != nil {
+		t.Fatalf("unexpected error creating wallet: %v", err)
+	}
+	wallet := walletIface.(*Wallet)
+
+	accountIface, err := wallet.CreateDistributedAccount(uuid.New(), "acc1", 1, shares[1].Value, vector, participants, 1, []byte("correct"))
+	if err != nil {
+		t.Fatalf("unexpected error creating distributed account: %v", err)
+	}
+	account := accountIface.(*Account)
+
+	if _, err := account.Reconstruct([]byte("wrong")); err == nil {
+		t.Error("expected an error reconstructing with the wrong passphrase, got none")
+	}
+}
+
+// realisticEncryptor is a trivial passphrase-checking types.Encryptor, just
+// enough to distinguish a correct passphrase from an incorrect one without
+// pulling in a real KDF/cipher.
+type realisticEncryptor struct{}
+
+func (realisticEncryptor) Encrypt(data []byte, passphrase string) ([]byte, error) {
+	return append([]byte(passphrase+"\x00"), data...), nil
+}
+
+func (realisticEncryptor) Decrypt(data []byte, passphrase string) ([]byte, error) {
+	prefix := []byte(passphrase + "\x00")
+	if !bytes.HasPrefix(data, prefix) {
+		return nil, errors.New("incorrect passphrase")
+	}
+	return data[len(prefix):], nil
+}
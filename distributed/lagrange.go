@@ -0,0 +1,60 @@
+// Copyright © 2019 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distributed
+
+import "math/big"
+
+// blsModulus is r, the order of the BLS12-381 scalar field over which secret shares
+// and their Lagrange coefficients are computed.
+var blsModulus, _ = new(big.Int).SetString("52435875175126190479447740508185965837690552500527637822603658699938581184513", 10)
+
+// lagrangeCoefficient computes the Lagrange basis coefficient, evaluated at x=0, for
+// participant i given the full set of participant IDs contributing a share or partial
+// signature, reduced modulo the BLS12-381 scalar field order.
+func lagrangeCoefficient(participants []uint64, i uint64) *big.Int {
+	xi := new(big.Int).SetUint64(i)
+	num := big.NewInt(1)
+	den := big.NewInt(1)
+	for _, j := range participants {
+		if j == i {
+			continue
+		}
+		xj := new(big.Int).SetUint64(j)
+		num.Mul(num, new(big.Int).Neg(xj))
+		num.Mod(num, blsModulus)
+		den.Mul(den, new(big.Int).Sub(xi, xj))
+		den.Mod(den, blsModulus)
+	}
+	den.Mod(den, blsModulus)
+	coeff := new(big.Int).Mul(num, new(big.Int).ModInverse(den, blsModulus))
+	return coeff.Mod(coeff, blsModulus)
+}
+
+// reconstructSecret combines shares (keyed by participant ID) at x=0 via Lagrange
+// interpolation to recover the polynomial's constant term, i.e. the original secret.
+// Callers must supply at least the reconstruction threshold's worth of shares.
+func reconstructSecret(shares map[uint64]*big.Int) *big.Int {
+	participants := make([]uint64, 0, len(shares))
+	for i := range shares {
+		participants = append(participants, i)
+	}
+
+	secret := big.NewInt(0)
+	for i, share := range shares {
+		term := new(big.Int).Mul(lagrangeCoefficient(participants, i), share)
+		secret.Add(secret, term)
+		secret.Mod(secret, blsModulus)
+	}
+	return secret
+}
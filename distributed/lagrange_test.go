@@ -0,0 +1,61 @@
+// Copyright © 2019 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distributed
+
+import (
+	"math/big"
+	"testing"
+)
+
+// share evaluates the test polynomial f(x) = secret + a1*x (mod blsModulus) used to
+// derive deterministic shares for the threshold reconstruction tests below.
+func share(secret, a1 *big.Int, x uint64) *big.Int {
+	term := new(big.Int).Mul(a1, new(big.Int).SetUint64(x))
+	s := new(big.Int).Add(secret, term)
+	return s.Mod(s, blsModulus)
+}
+
+func TestReconstructSecret(t *testing.T) {
+	secret := big.NewInt(123456789)
+	a1 := big.NewInt(987654321)
+
+	shares := map[uint64]*big.Int{
+		1: share(secret, a1, 1),
+		2: share(secret, a1, 2),
+		3: share(secret, a1, 3),
+	}
+
+	tests := []struct {
+		name         string
+		participants []uint64
+	}{
+		{name: "FirstTwo", participants: []uint64{1, 2}},
+		{name: "LastTwo", participants: []uint64{2, 3}},
+		{name: "FirstAndLast", participants: []uint64{1, 3}},
+		{name: "AllThree", participants: []uint64{1, 2, 3}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			subset := make(map[uint64]*big.Int)
+			for _, i := range test.participants {
+				subset[i] = shares[i]
+			}
+			reconstructed := reconstructSecret(subset)
+			if reconstructed.Cmp(secret) != 0 {
+				t.Errorf("expected %s, got %s", secret.String(), reconstructed.String())
+			}
+		})
+	}
+}
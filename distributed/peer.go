@@ -0,0 +1,77 @@
+// Copyright © 2019 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distributed
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+)
+
+// httpPeerClient is the default PeerClient: it POSTs a JSON request carrying the
+// account ID and data to sign to the peer's endpoint, and expects a JSON response
+// carrying the partial signature.
+type httpPeerClient struct{}
+
+type partialSignatureRequest struct {
+	AccountID uuid.UUID `json:"account_id"`
+	Data      string    `json:"data"`
+}
+
+type partialSignatureResponse struct {
+	Signature string `json:"signature"`
+}
+
+// RequestPartialSignature asks a single peer to sign data with its share of the
+// named account's private key.
+func (c *httpPeerClient) RequestPartialSignature(ctx context.Context, endpoint string, accountID uuid.UUID, data []byte) (e2types.Signature, error) {
+	reqBody, err := json.Marshal(&partialSignatureRequest{
+		AccountID: accountID,
+		Data:      hex.EncodeToString(data),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("peer %s returned status %d", endpoint, resp.StatusCode)
+	}
+
+	partialResp := &partialSignatureResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(partialResp); err != nil {
+		return nil, err
+	}
+	sigBytes, err := hex.DecodeString(partialResp.Signature)
+	if err != nil {
+		return nil, err
+	}
+	return e2types.BLSSignatureFromBytes(sigBytes)
+}
@@ -0,0 +1,85 @@
+// Copyright © 2019 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distributed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// ShareClient requests a participant's raw private key share for an account from a
+// single peer endpoint. Unlike PeerClient, which never reveals a share, this is
+// only ever used for an explicit, operator-initiated reconstruction (for example
+// converting a distributed account back to a plain "nd" account via Reconstruct)
+// and should only be pointed at trusted peers.
+type ShareClient interface {
+	RequestShare(ctx context.Context, endpoint string, accountID uuid.UUID) (*big.Int, error)
+}
+
+var shareClient ShareClient = &httpShareClient{}
+
+// SetShareClient overrides the ShareClient used by Reconstruct, for deployments
+// whose peers speak a protocol other than the default HTTP JSON API.
+func SetShareClient(client ShareClient) {
+	shareClient = client
+}
+
+type httpShareClient struct{}
+
+type shareRequest struct {
+	AccountID uuid.UUID `json:"account_id"`
+}
+
+type shareResponse struct {
+	Share string `json:"share"`
+}
+
+// RequestShare asks a single peer for its raw share of the named account's private key.
+func (c *httpShareClient) RequestShare(ctx context.Context, endpoint string, accountID uuid.UUID) (*big.Int, error) {
+	reqBody, err := json.Marshal(&shareRequest{AccountID: accountID})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("peer %s returned status %d", endpoint, resp.StatusCode)
+	}
+
+	shareResp := &shareResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(shareResp); err != nil {
+		return nil, err
+	}
+	share, ok := new(big.Int).SetString(shareResp.Share, 16)
+	if !ok {
+		return nil, fmt.Errorf("peer %s returned an invalid share", endpoint)
+	}
+	return share, nil
+}
@@ -0,0 +1,118 @@
+// Copyright © 2019 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distributed
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+)
+
+// Share is a single participant's share of a split private key: the value of the
+// splitting polynomial evaluated at that participant's ID.
+type Share struct {
+	ParticipantID uint64
+	Value         *big.Int
+}
+
+// VerificationVector is the set of Feldman commitments to a split polynomial's
+// coefficients (the public key corresponding to each coefficient), letting a share
+// be verified against the composite public key without reconstructing the private
+// key. VerificationVector[0] is the commitment to the secret itself, and so is the
+// composite public key of the split account.
+type VerificationVector [][]byte
+
+// Split divides privateKey into a share per entry in participants, of which any
+// signingThreshold can later reconstruct the original key (or a signature made
+// with it) via Lagrange interpolation. It follows Shamir's secret sharing with
+// Feldman verifiable commitments to the polynomial coefficients.
+func Split(privateKey e2types.PrivateKey, signingThreshold uint32, participants map[uint64]string) (map[uint64]*Share, VerificationVector, error) {
+	secret := new(big.Int).SetBytes(privateKey.Marshal())
+	secret.Mod(secret, blsModulus)
+
+	coefficients := make([]*big.Int, signingThreshold)
+	coefficients[0] = secret
+	for i := uint32(1); i < signingThreshold; i++ {
+		c, err := rand.Int(rand.Reader, blsModulus)
+		if err != nil {
+			return nil, nil, err
+		}
+		coefficients[i] = c
+	}
+
+	vector := make(VerificationVector, len(coefficients))
+	for i, c := range coefficients {
+		commitmentKey, err := e2types.BLSPrivateKeyFromBytes(leftPad32(c.Bytes()))
+		if err != nil {
+			return nil, nil, err
+		}
+		vector[i] = commitmentKey.PublicKey().Marshal()
+	}
+
+	shares := make(map[uint64]*Share, len(participants))
+	for id := range participants {
+		x := new(big.Int).SetUint64(id)
+		shares[id] = &Share{ParticipantID: id, Value: polyEval(coefficients, x)}
+	}
+
+	return shares, vector, nil
+}
+
+// polyEval evaluates the polynomial defined by coefficients (constant term first) at x, modulo blsModulus.
+func polyEval(coefficients []*big.Int, x *big.Int) *big.Int {
+	result := big.NewInt(0)
+	power := big.NewInt(1)
+	for _, c := range coefficients {
+		term := new(big.Int).Mul(c, power)
+		result.Add(result, term)
+		result.Mod(result, blsModulus)
+		power.Mul(power, x)
+		power.Mod(power, blsModulus)
+	}
+	return result
+}
+
+// LocalParticipant returns the single participant ID in participants that is
+// registered with an empty endpoint, the convention this package uses to mark
+// "this node" rather than a remote peer. It is an error for there to be none or
+// more than one.
+func LocalParticipant(participants map[uint64]string) (uint64, error) {
+	var found uint64
+	var ok bool
+	for id, endpoint := range participants {
+		if endpoint == "" {
+			if ok {
+				return 0, fmt.Errorf("more than one participant is registered as the local share holder")
+			}
+			found, ok = id, true
+		}
+	}
+	if !ok {
+		return 0, fmt.Errorf("no participant is registered as the local share holder")
+	}
+	return found, nil
+}
+
+// leftPad32 left-pads b with zero bytes to 32 bytes, or truncates it to its
+// trailing 32 bytes, matching the fixed-width scalar encoding e2types expects.
+func leftPad32(b []byte) []byte {
+	if len(b) >= 32 {
+		return b[len(b)-32:]
+	}
+	padded := make([]byte, 32)
+	copy(padded[32-len(b):], b)
+	return padded
+}
@@ -0,0 +1,81 @@
+// Copyright © 2019 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distributed
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestPolyEvalMatchesShares(t *testing.T) {
+	secret := big.NewInt(123456789)
+	a1 := big.NewInt(987654321)
+	coefficients := []*big.Int{secret, a1}
+
+	for x := uint64(1); x <= 3; x++ {
+		got := polyEval(coefficients, new(big.Int).SetUint64(x))
+		want := share(secret, a1, x)
+		if got.Cmp(want) != 0 {
+			t.Errorf("x=%d: expected %s, got %s", x, want.String(), got.String())
+		}
+	}
+
+	// The polynomial evaluated at 0 is just its constant term, the secret.
+	if got := polyEval(coefficients, big.NewInt(0)); got.Cmp(secret) != 0 {
+		t.Errorf("expected f(0)=%s, got %s", secret.String(), got.String())
+	}
+}
+
+func TestLocalParticipant(t *testing.T) {
+	tests := []struct {
+		name         string
+		participants map[uint64]string
+		want         uint64
+		wantErr      bool
+	}{
+		{
+			name:         "SingleLocal",
+			participants: map[uint64]string{1: "", 2: "https://peer2.example.com", 3: "https://peer3.example.com"},
+			want:         1,
+		},
+		{
+			name:         "NoLocal",
+			participants: map[uint64]string{2: "https://peer2.example.com", 3: "https://peer3.example.com"},
+			wantErr:      true,
+		},
+		{
+			name:         "MultipleLocal",
+			participants: map[uint64]string{1: "", 2: ""},
+			wantErr:      true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := LocalParticipant(test.participants)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("expected participant %d, got %d", test.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright © 2019 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package distributed provides a wallet whose accounts hold only a threshold
+// share of their private key, the remainder being held by named peers. Signing
+// gathers partial signatures from at least SigningThreshold of those peers and
+// recombines them by Lagrange interpolation, so signing never reconstructs the
+// composite private key; Account.Reconstruct is the one operation that does. A wallet opened without peer contact can still verify the
+// composite public key and participant set of each account ("verify-only" mode).
+package distributed
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/google/uuid"
+	ecodec "github.com/wealdtech/go-ecodec"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
+	types "github.com/wealdtech/go-eth2-wallet-types"
+)
+
+// Wallet is a distributed wallet.
+type Wallet struct {
+	id        uuid.UUID
+	name      string
+	store     types.Store
+	encryptor types.Encryptor
+	accounts  map[uuid.UUID]*Account
+}
+
+type walletJSON struct {
+	ID   uuid.UUID `json:"uuid"`
+	Name string    `json:"name"`
+	Type string    `json:"type"`
+}
+
+// CreateWallet creates a new, empty distributed wallet.
+func CreateWallet(name string, store types.Store, encryptor types.Encryptor) (types.Wallet, error) {
+	if _, err := store.RetrieveWallet(name); err == nil {
+		return nil, fmt.Errorf("wallet %q already exists", name)
+	}
+
+	wallet := &Wallet{
+		id:        uuid.New(),
+		name:      name,
+		store:     store,
+		encryptor: encryptor,
+		accounts:  make(map[uuid.UUID]*Account),
+	}
+	if err := wallet.storeWallet(); err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+// DeserializeWallet turns bytes from the store back in to a distributed wallet.
+func DeserializeWallet(data []byte, store types.Store, encryptor types.Encryptor) (types.Wallet, error) {
+	info := &walletJSON{}
+	if err := json.Unmarshal(data, info); err != nil {
+		return nil, err
+	}
+
+	wallet := &Wallet{
+		id:        info.ID,
+		name:      info.Name,
+		store:     store,
+		encryptor: encryptor,
+		accounts:  make(map[uuid.UUID]*Account),
+	}
+	for accountData := range store.RetrieveAccounts(wallet.id) {
+		account, err := deserializeAccount(wallet, accountData)
+		if err != nil {
+			return nil, err
+		}
+		wallet.accounts[account.id] = account
+	}
+	return wallet, nil
+}
+
+// Import turns an ecodec export back in to a distributed wallet, preserving the
+// verification_vector, composite_public_key, signing_threshold and participants
+// of each account unchanged.
+func Import(encryptedData []byte, passphrase []byte, store types.Store, encryptor types.Encryptor) (types.Wallet, error) {
+	data, err := ecodec.Decrypt(encryptedData, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	type walletExt struct {
+		Wallet   *walletJSON       `json:"wallet"`
+		Accounts []json.RawMessage `json:"accounts"`
+	}
+	ext := &walletExt{}
+	if err := json.Unmarshal(data, ext); err != nil {
+		return nil, err
+	}
+
+	wallet := &Wallet{
+		id:        ext.Wallet.ID,
+		name:      ext.Wallet.Name,
+		store:     store,
+		encryptor: encryptor,
+		accounts:  make(map[uuid.UUID]*Account),
+	}
+	for _, accountData := range ext.Accounts {
+		account, err := deserializeAccount(wallet, accountData)
+		if err != nil {
+			return nil, err
+		}
+		wallet.accounts[account.id] = account
+	}
+
+	if err := wallet.storeWallet(); err != nil {
+		return nil, err
+	}
+	for _, account := range wallet.accounts {
+		if err := wallet.storeAccount(account); err != nil {
+			return nil, err
+		}
+	}
+	return wallet, nil
+}
+
+func (w *Wallet) storeWallet() error {
+	data, err := json.Marshal(&walletJSON{ID: w.id, Name: w.name, Type: "distributed"})
+	if err != nil {
+		return err
+	}
+	return w.store.StoreWallet(w.id, w.name, data)
+}
+
+func (w *Wallet) storeAccount(account *Account) error {
+	data, err := json.Marshal(account.toJSON())
+	if err != nil {
+		return err
+	}
+	return w.store.StoreAccount(w.id, account.id, data)
+}
+
+// ID provides the ID for the wallet.
+func (w *Wallet) ID() uuid.UUID { return w.id }
+
+// Name provides the name for the wallet.
+func (w *Wallet) Name() string { return w.name }
+
+// Type provides the type for the wallet.
+func (w *Wallet) Type() string { return "distributed" }
+
+// Unlock is a no-op for distributed wallets: they hold no wallet-level secret,
+// only per-account shares unlocked individually via Account.Unlock.
+func (w *Wallet) Unlock(passphrase []byte) error { return nil }
+
+// Lock is a no-op for distributed wallets: they hold no wallet-level secret,
+// only per-account shares locked individually via Account.Lock.
+func (w *Wallet) Lock() error { return nil }
+
+// Accounts provides the accounts held by the wallet.
+func (w *Wallet) Accounts() <-chan types.Account {
+	ch := make(chan types.Account, 1024)
+	go func() {
+		for _, account := range w.accounts {
+			ch <- account
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+// AccountByName provides an account given its name.
+func (w *Wallet) AccountByName(name string) (types.Account, error) {
+	for _, account := range w.accounts {
+		if account.name == name {
+			return account, nil
+		}
+	}
+	return nil, fmt.Errorf("no account with name %q", name)
+}
+
+// AccountByID provides an account given its ID.
+func (w *Wallet) AccountByID(id uuid.UUID) (types.Account, error) {
+	account, exists := w.accounts[id]
+	if !exists {
+		return nil, fmt.Errorf("no account with ID %q", id)
+	}
+	return account, nil
+}
+
+// CreateAccount is not supported for distributed wallets, whose accounts require a
+// pre-split private key: use CreateDistributedAccount instead.
+func (w *Wallet) CreateAccount(name string, passphrase []byte) (types.Account, error) {
+	return nil, fmt.Errorf("distributed accounts must be created with CreateDistributedAccount")
+}
+
+// CreateDistributedAccount adds an account to the wallet given one share of an
+// already-split private key, the verification vector committing to the full
+// split, the participant set holding the other shares, and the threshold of
+// partial signatures required to reconstruct a group signature. It is normally
+// called once per participant as part of a Split (see ConvertWallet), with id
+// and the rest of the account's public data identical across every
+// participant's own copy and only localParticipantID/share differing.
+func (w *Wallet) CreateDistributedAccount(id uuid.UUID, name string, localParticipantID uint64, share *big.Int, vector VerificationVector, participants map[uint64]string, signingThreshold uint32, passphrase []byte) (types.Account, error) {
+	if _, err := w.AccountByName(name); err == nil {
+		return nil, fmt.Errorf("account %q already exists", name)
+	}
+	if _, ok := participants[localParticipantID]; !ok {
+		return nil, fmt.Errorf("participant %d is not a member of the participant set", localParticipantID)
+	}
+
+	compositePublicKey, err := e2types.BLSPublicKeyFromBytes(vector[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid verification vector: %v", err)
+	}
+
+	account := &Account{
+		id:                 id,
+		name:               name,
+		wallet:             w,
+		compositePublicKey: compositePublicKey,
+		verificationVector: vector,
+		participants:       participants,
+		signingThreshold:   signingThreshold,
+		localID:            localParticipantID,
+	}
+
+	if share != nil {
+		encryptedShare, err := w.encryptor.Encrypt(leftPad32(share.Bytes()), string(passphrase))
+		if err != nil {
+			return nil, err
+		}
+		account.encryptedShare = encryptedShare
+		account.share = share
+		account.unlocked = true
+	}
+
+	if err := w.storeAccount(account); err != nil {
+		return nil, err
+	}
+	w.accounts[account.id] = account
+	return account, nil
+}
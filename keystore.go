@@ -0,0 +1,254 @@
+// Copyright © 2019 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wallet
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	types "github.com/wealdtech/go-eth2-wallet-types"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// eip2335Keystore is the subset of the EIP-2335 keystore JSON format
+// (https://eips.ethereum.org/EIPS/eip-2335) needed to decrypt the account it contains.
+type eip2335Keystore struct {
+	Crypto eip2335Crypto `json:"crypto"`
+	Path   string        `json:"path"`
+}
+
+type eip2335Crypto struct {
+	KDF      eip2335Module `json:"kdf"`
+	Checksum eip2335Module `json:"checksum"`
+	Cipher   eip2335Module `json:"cipher"`
+}
+
+type eip2335Module struct {
+	Function string          `json:"function"`
+	Params   json.RawMessage `json:"params"`
+	Message  string          `json:"message"`
+}
+
+// keymanagerOpts is the subset of Prysm's keymanageropts.json bundle format needed to
+// locate the keystores and shared passphrase file it references.
+type keymanagerOpts struct {
+	Keystores      []string `json:"keystores"`
+	PassphrasePath string   `json:"passphrasePath"`
+}
+
+// isEIP2335Keystore reports whether data is a single EIP-2335 keystore, identified by
+// its crypto.{kdf,checksum,cipher} tri-part structure, as opposed to this package's
+// own ecodec export envelope.
+func isEIP2335Keystore(data []byte) bool {
+	keystore := &eip2335Keystore{}
+	if err := json.Unmarshal(data, keystore); err != nil {
+		return false
+	}
+	return keystore.Crypto.KDF.Function != "" && keystore.Crypto.Checksum.Function != "" && keystore.Crypto.Cipher.Function != ""
+}
+
+// isKeymanagerOpts reports whether data is a Prysm keymanageropts.json bundle.
+func isKeymanagerOpts(data []byte) bool {
+	opts := &keymanagerOpts{}
+	if err := json.Unmarshal(data, opts); err != nil {
+		return false
+	}
+	return len(opts.Keystores) > 0
+}
+
+// ImportKeystore imports a single EIP-2335 keystore, or a Prysm keymanageropts.json
+// bundle referencing one or more keystores plus a shared passphrase file, as accounts
+// of a new or existing "nd" wallet. Bulk imports are named "prefix/0".."prefix/N-1"
+// using WithAccountNamePrefix; a single keystore's account defaults to its "path"
+// field when no prefix is given.
+func ImportKeystore(data []byte, passphrase []byte, opts ...Option) ([]types.Account, error) {
+	options := walletOptions{
+		store:     store,
+		encryptor: encryptor,
+	}
+	for _, o := range opts {
+		o.apply(&options)
+	}
+
+	switch {
+	case isKeymanagerOpts(data):
+		return importKeymanagerOpts(data, passphrase, options)
+	case isEIP2335Keystore(data):
+		account, err := importEIP2335Keystore(data, passphrase, options, 0)
+		if err != nil {
+			return nil, err
+		}
+		return []types.Account{account}, nil
+	default:
+		return nil, fmt.Errorf("data is not an EIP-2335 keystore or keymanageropts bundle")
+	}
+}
+
+// importKeymanagerOpts reads a Prysm keymanageropts.json bundle, decrypting each
+// referenced keystore with the passphrase found at its passphrasePath.
+func importKeymanagerOpts(data []byte, fallbackPassphrase []byte, options walletOptions) ([]types.Account, error) {
+	opts := &keymanagerOpts{}
+	if err := json.Unmarshal(data, opts); err != nil {
+		return nil, err
+	}
+
+	passphrase := fallbackPassphrase
+	if opts.PassphrasePath != "" {
+		contents, err := ioutil.ReadFile(opts.PassphrasePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read passphrase file %q: %v", opts.PassphrasePath, err)
+		}
+		passphrase = []byte(strings.TrimSpace(string(contents)))
+	}
+
+	accounts := make([]types.Account, 0, len(opts.Keystores))
+	for i, path := range opts.Keystores {
+		keystoreData, err := ioutil.ReadFile(path)
+		if err != nil {
+			return accounts, fmt.Errorf("failed to read keystore %q: %v", path, err)
+		}
+		account, err := importEIP2335Keystore(keystoreData, passphrase, options, i)
+		if err != nil {
+			return accounts, fmt.Errorf("failed to import keystore %q: %v", path, err)
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
+
+// importEIP2335Keystore decrypts a single EIP-2335 keystore and stores it as an
+// account of a new or existing "nd" wallet, naming it from WithAccountNamePrefix and
+// index when given, falling back to the keystore's HD path.
+func importEIP2335Keystore(data []byte, passphrase []byte, options walletOptions, index int) (types.Account, error) {
+	keystore := &eip2335Keystore{}
+	if err := json.Unmarshal(data, keystore); err != nil {
+		return nil, err
+	}
+
+	secret, err := decryptEIP2335(keystore.Crypto, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	walletName := options.walletName
+	if walletName == "" {
+		walletName = "imported"
+	}
+	wallet, err := OpenWallet(walletName, WithStore(options.store), WithEncryptor(options.encryptor))
+	if err != nil {
+		wallet, err = CreateWallet(walletName, WithType("nd"), WithStore(options.store), WithEncryptor(options.encryptor))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create wallet %q: %v", walletName, err)
+		}
+	}
+	importer, ok := wallet.(types.WalletAccountImporter)
+	if !ok {
+		return nil, fmt.Errorf("wallet %q cannot import accounts with existing keys", walletName)
+	}
+
+	name := keystore.Path
+	if options.accountNamePrefix != "" {
+		name = fmt.Sprintf("%s/%d", options.accountNamePrefix, index)
+	}
+	return importer.ImportAccount(name, secret, passphrase)
+}
+
+// decryptEIP2335 derives the decryption key from crypto.kdf, verifies it against
+// crypto.checksum, and decrypts crypto.cipher to recover the raw private key.
+func decryptEIP2335(crypto eip2335Crypto, passphrase []byte) ([]byte, error) {
+	dk, err := deriveEIP2335Key(crypto.KDF, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	cipherMsg, err := hex.DecodeString(crypto.Cipher.Message)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cipher message: %v", err)
+	}
+
+	checksum := sha256.Sum256(append(dk[16:32], cipherMsg...))
+	if hex.EncodeToString(checksum[:]) != crypto.Checksum.Message {
+		return nil, fmt.Errorf("invalid passphrase")
+	}
+
+	switch crypto.Cipher.Function {
+	case "aes-128-ctr":
+		var params struct {
+			IV string `json:"iv"`
+		}
+		if err := json.Unmarshal(crypto.Cipher.Params, &params); err != nil {
+			return nil, err
+		}
+		iv, err := hex.DecodeString(params.IV)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cipher IV: %v", err)
+		}
+		block, err := aes.NewCipher(dk[:16])
+		if err != nil {
+			return nil, err
+		}
+		secret := make([]byte, len(cipherMsg))
+		cipher.NewCTR(block, iv).XORKeyStream(secret, cipherMsg)
+		return secret, nil
+	default:
+		return nil, fmt.Errorf("unsupported cipher function %q", crypto.Cipher.Function)
+	}
+}
+
+// deriveEIP2335Key derives the 32-byte decryption key for a keystore using the KDF
+// (scrypt or pbkdf2) declared in its crypto.kdf section.
+func deriveEIP2335Key(kdf eip2335Module, passphrase []byte) ([]byte, error) {
+	switch kdf.Function {
+	case "scrypt":
+		var params struct {
+			DKLen int    `json:"dklen"`
+			N     int    `json:"n"`
+			P     int    `json:"p"`
+			R     int    `json:"r"`
+			Salt  string `json:"salt"`
+		}
+		if err := json.Unmarshal(kdf.Params, &params); err != nil {
+			return nil, err
+		}
+		salt, err := hex.DecodeString(params.Salt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid kdf salt: %v", err)
+		}
+		return scrypt.Key(passphrase, salt, params.N, params.R, params.P, params.DKLen)
+	case "pbkdf2":
+		var params struct {
+			DKLen int    `json:"dklen"`
+			C     int    `json:"c"`
+			PRF   string `json:"prf"`
+			Salt  string `json:"salt"`
+		}
+		if err := json.Unmarshal(kdf.Params, &params); err != nil {
+			return nil, err
+		}
+		salt, err := hex.DecodeString(params.Salt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid kdf salt: %v", err)
+		}
+		return pbkdf2.Key(passphrase, salt, params.C, params.DKLen, sha256.New), nil
+	default:
+		return nil, fmt.Errorf("unsupported kdf function %q", kdf.Function)
+	}
+}
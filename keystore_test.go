@@ -0,0 +1,108 @@
+// Copyright © 2019 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wallet
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+)
+
+func TestDecryptEIP2335(t *testing.T) {
+	tests := []struct {
+		name       string
+		crypto     string
+		passphrase string
+		secret     string
+		err        string
+	}{
+		{
+			name:       "PBKDF2",
+			passphrase: "testpassword",
+			crypto: `{
+				"kdf": {"function": "pbkdf2", "params": {"dklen": 32, "c": 1024, "prf": "hmac-sha256", "salt": "83ece095309298ad1aa788bf56208c7bd86d1f476b802357f47b77ce90054881"}, "message": ""},
+				"checksum": {"function": "sha256", "params": {}, "message": "c3287f716cac37cdadfe3e94196c13f090b65c98ae966aa2ac2a528a6867b95a"},
+				"cipher": {"function": "aes-128-ctr", "params": {"iv": "b6ab39bebb6ef18ad90f0bc544a8742c"}, "message": "d62ded13e2d6c11bb42569047fcb4d6b24c2c3a637542103ecd7fbe3d84126bd"}
+			}`,
+			secret: "8ab6b6de3d6c315f7a9ffeb4f3b40c84c64394679d23683b5eaefec1b2d54df3",
+		},
+		{
+			name:       "Scrypt",
+			passphrase: "testpassword",
+			crypto: `{
+				"kdf": {"function": "scrypt", "params": {"dklen": 32, "n": 1024, "r": 8, "p": 1, "salt": "cbfcbb691401fde4e4fd9b809bffa8d8ff6904ac0b9b399397dbcb22a7e01159"}, "message": ""},
+				"checksum": {"function": "sha256", "params": {}, "message": "54df335a8741a888cbf752ab6a5d2c4eddfdf0f7a2b5fef820dfdc5359a705c6"},
+				"cipher": {"function": "aes-128-ctr", "params": {"iv": "ed839f24d30aea1a336c488da21995c1"}, "message": "709a78bbf56ea60ac6a79a9277961fa25bebb63bad0d961e05e2ca731a06f6b3"}
+			}`,
+			secret: "4050187e0666625cbefd48a86a54e37870e3073555a7f164e4098527e0d12491",
+		},
+		{
+			name:       "WrongPassphrase",
+			passphrase: "not the right passphrase",
+			crypto: `{
+				"kdf": {"function": "pbkdf2", "params": {"dklen": 32, "c": 1024, "prf": "hmac-sha256", "salt": "83ece095309298ad1aa788bf56208c7bd86d1f476b802357f47b77ce90054881"}, "message": ""},
+				"checksum": {"function": "sha256", "params": {}, "message": "c3287f716cac37cdadfe3e94196c13f090b65c98ae966aa2ac2a528a6867b95a"},
+				"cipher": {"function": "aes-128-ctr", "params": {"iv": "b6ab39bebb6ef18ad90f0bc544a8742c"}, "message": "d62ded13e2d6c11bb42569047fcb4d6b24c2c3a637542103ecd7fbe3d84126bd"}
+			}`,
+			err: "invalid passphrase",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			crypto := eip2335Crypto{}
+			if err := json.Unmarshal([]byte(test.crypto), &crypto); err != nil {
+				t.Fatalf("failed to parse test crypto: %v", err)
+			}
+
+			secret, err := decryptEIP2335(crypto, []byte(test.passphrase))
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("expected error %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expected, err := hex.DecodeString(test.secret)
+			if err != nil {
+				t.Fatalf("invalid test secret: %v", err)
+			}
+			if hex.EncodeToString(secret) != hex.EncodeToString(expected) {
+				t.Errorf("expected secret %x, got %x", expected, secret)
+			}
+		})
+	}
+}
+
+func TestIsEIP2335Keystore(t *testing.T) {
+	keystore := []byte(`{"crypto": {"kdf": {"function": "pbkdf2"}, "checksum": {"function": "sha256"}, "cipher": {"function": "aes-128-ctr"}}}`)
+	if !isEIP2335Keystore(keystore) {
+		t.Errorf("expected keystore to be recognised as EIP-2335")
+	}
+	if isEIP2335Keystore([]byte(`{"keystores": ["ks1.json"]}`)) {
+		t.Errorf("did not expect keymanageropts bundle to be recognised as EIP-2335")
+	}
+}
+
+func TestIsKeymanagerOpts(t *testing.T) {
+	opts := []byte(`{"keystores": ["ks1.json", "ks2.json"], "passphrasePath": "pass.txt"}`)
+	if !isKeymanagerOpts(opts) {
+		t.Errorf("expected bundle to be recognised as keymanageropts")
+	}
+	if isKeymanagerOpts([]byte(`{"crypto": {"kdf": {"function": "pbkdf2"}}}`)) {
+		t.Errorf("did not expect a keystore to be recognised as keymanageropts")
+	}
+}
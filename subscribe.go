@@ -0,0 +1,300 @@
+// Copyright © 2019 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wallet
+
+import (
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/fsnotify/fsnotify"
+	"github.com/google/uuid"
+	types "github.com/wealdtech/go-eth2-wallet-types"
+)
+
+// defaultWatchInterval is used by Subscribe's background poller when
+// WithWatchInterval is not given.
+const defaultWatchInterval = 30 * time.Second
+
+// EventKind describes what happened to a wallet or account in a WalletEvent.
+type EventKind int
+
+const (
+	// Added indicates a wallet or account appeared in the store.
+	Added EventKind = iota
+	// Removed indicates a wallet or account disappeared from the store.
+	Removed
+	// Modified indicates a wallet or account's contents changed.
+	Modified
+)
+
+// WalletEvent is sent to subscribers registered with Subscribe when the backing
+// store's contents change. AccountName is empty for events about the wallet itself.
+type WalletEvent struct {
+	Kind        EventKind
+	WalletName  string
+	AccountName string
+}
+
+// pathedStore is an optional extension to types.Store implemented by
+// store_filesystem, letting Subscribe watch its directory with fsnotify for
+// immediate re-scans rather than waiting for the next poll.
+type pathedStore interface {
+	Location() string
+}
+
+// accountState is the cached snapshot of an account used to detect changes
+// between scans: its name, plus a fingerprint of its public content so that a
+// rename and a content change (for example a rotated distributed share
+// changing the composite public key) are both detected, not just the former.
+type accountState struct {
+	name        string
+	fingerprint [32]byte
+}
+
+var (
+	walletFeed   event.Feed
+	watchMu      sync.Mutex
+	watching     bool
+	watchStop    chan struct{}
+	subscribers  int
+	watchedNames = make(map[uuid.UUID]string)
+	watchedAccs  = make(map[uuid.UUID]map[uuid.UUID]accountState)
+)
+
+// Subscribe registers ch to receive a WalletEvent whenever an account or wallet is
+// added to, removed from, or modified in the backing store. The first call to
+// Subscribe seeds an internal cache with a scan of the store (mirroring Wallets())
+// and starts a background poller, at WithWatchInterval's interval, that re-lists
+// the store and diffs it against the cache to generate events; for store_filesystem
+// it additionally uses fsnotify to trigger an immediate re-scan on directory
+// changes. The background goroutines run only while at least one subscriber is
+// registered: call the returned subscription's Unsubscribe to detach ch, and once
+// the last subscriber has unsubscribed they are stopped.
+func Subscribe(ch chan<- WalletEvent, opts ...Option) event.Subscription {
+	options := walletOptions{
+		watchInterval: defaultWatchInterval,
+	}
+	for _, o := range opts {
+		o.apply(&options)
+	}
+
+	watchMu.Lock()
+	subscribers++
+	if !watching {
+		watching = true
+		watchStop = make(chan struct{})
+		scanAndDiffLocked()
+		go pollWallets(options.watchInterval, watchStop)
+		if ps, ok := store.(pathedStore); ok {
+			go watchFilesystem(ps.Location(), watchStop)
+		}
+	}
+	watchMu.Unlock()
+
+	return &refCountedSubscription{Subscription: walletFeed.Subscribe(ch)}
+}
+
+// refCountedSubscription decrements the subscriber count on Unsubscribe,
+// stopping the background poller and filesystem watcher once the last
+// subscriber has detached.
+type refCountedSubscription struct {
+	event.Subscription
+}
+
+// Unsubscribe detaches the channel from walletFeed and, if this was the last
+// remaining subscriber, signals the background goroutines started by
+// Subscribe to stop.
+func (s *refCountedSubscription) Unsubscribe() {
+	s.Subscription.Unsubscribe()
+
+	watchMu.Lock()
+	defer watchMu.Unlock()
+	subscribers--
+	if subscribers <= 0 {
+		subscribers = 0
+		if watching {
+			watching = false
+			close(watchStop)
+		}
+	}
+}
+
+// pollWallets re-scans the store at the given interval, emitting a WalletEvent
+// for each change found, until stop is closed.
+func pollWallets(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			scanAndDiff()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// watchFilesystem triggers an immediate re-scan whenever the filesystem store's
+// directory tree changes, so additions (for example, share files written by a
+// distributed wallet's peers into a per-wallet subdirectory) are picked up
+// without waiting for the next poll. It runs until stop is closed.
+func watchFilesystem(root string, stop <-chan struct{}) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return
+	}
+	defer watcher.Close()
+
+	if err := addWatchRecursive(watcher, root); err != nil {
+		return
+	}
+
+	for {
+		select {
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			// A newly created subdirectory (for example a new wallet's own
+			// directory) needs its own watch added, since fsnotify watches are
+			// not recursive.
+			if ev.Op&fsnotify.Create != 0 {
+				if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+					watcher.Add(ev.Name)
+				}
+			}
+			scanAndDiff()
+		case _, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// addWatchRecursive adds a watch on root and every directory beneath it, so
+// that changes to per-wallet subdirectories (where accounts and, for
+// distributed wallets, share files actually live) are observed.
+func addWatchRecursive(watcher *fsnotify.Watcher, root string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return watcher.Add(path)
+		}
+		return nil
+	})
+}
+
+// scanAndDiff acquires watchMu and runs scanAndDiffLocked. Callers that
+// already hold watchMu (Subscribe, seeding the initial scan) must call
+// scanAndDiffLocked directly instead, since sync.Mutex is not reentrant.
+func scanAndDiff() {
+	watchMu.Lock()
+	defer watchMu.Unlock()
+	scanAndDiffLocked()
+}
+
+// scanAndDiffLocked lists every wallet and account in the store, compares the
+// result against the cache built by the previous scan, emits a WalletEvent for
+// each addition, removal or modification found, and replaces the cache with
+// the new state. Callers must hold watchMu.
+func scanAndDiffLocked() {
+	seenWallets := make(map[uuid.UUID]string)
+	seenAccs := make(map[uuid.UUID]map[uuid.UUID]accountState)
+
+	for wallet := range Wallets() {
+		seenWallets[wallet.ID()] = wallet.Name()
+		accs := make(map[uuid.UUID]accountState)
+		for account := range wallet.Accounts() {
+			accs[account.ID()] = accountState{name: account.Name(), fingerprint: fingerprintAccount(account)}
+		}
+		seenAccs[wallet.ID()] = accs
+
+		for _, ev := range diffAccounts(wallet.Name(), watchedAccs[wallet.ID()], accs) {
+			walletFeed.Send(ev)
+		}
+		if _, ok := watchedWallet(wallet.ID()); !ok {
+			walletFeed.Send(WalletEvent{Kind: Added, WalletName: wallet.Name()})
+		}
+	}
+
+	for id, prevAccs := range watchedAccs {
+		name := watchedNames[id]
+		_, stillExists := seenAccs[id]
+		if stillExists {
+			continue
+		}
+		for _, accState := range prevAccs {
+			walletFeed.Send(WalletEvent{Kind: Removed, WalletName: name, AccountName: accState.name})
+		}
+		walletFeed.Send(WalletEvent{Kind: Removed, WalletName: name})
+	}
+
+	watchedNames = seenWallets
+	watchedAccs = seenAccs
+}
+
+// diffAccounts compares a wallet's previously cached account states against
+// its freshly scanned ones and returns the Added, Removed and Modified events
+// the difference implies. It is a pure function of its inputs so that the
+// diffing logic can be tested without a real store or wallet.
+func diffAccounts(walletName string, prevAccs, currAccs map[uuid.UUID]accountState) []WalletEvent {
+	var events []WalletEvent
+
+	for id, curr := range currAccs {
+		prev, existed := prevAccs[id]
+		switch {
+		case !existed:
+			events = append(events, WalletEvent{Kind: Added, WalletName: walletName, AccountName: curr.name})
+		case prev.fingerprint != curr.fingerprint:
+			events = append(events, WalletEvent{Kind: Modified, WalletName: walletName, AccountName: curr.name})
+		}
+	}
+
+	for id, prev := range prevAccs {
+		if _, stillExists := currAccs[id]; !stillExists {
+			events = append(events, WalletEvent{Kind: Removed, WalletName: walletName, AccountName: prev.name})
+		}
+	}
+
+	return events
+}
+
+// fingerprintAccount hashes the public content of account -- its name and
+// public key -- so that scanAndDiff notices real content changes (a rotated
+// distributed share producing a new composite public key, for example), not
+// just a renamed account.
+func fingerprintAccount(account types.Account) [32]byte {
+	h := sha256.New()
+	h.Write([]byte(account.Name()))
+	if pk := account.PublicKey(); pk != nil {
+		h.Write(pk.Marshal())
+	}
+	var out [32]byte
+	copy(out[:], h.Sum(nil))
+	return out
+}
+
+func watchedWallet(id uuid.UUID) (string, bool) {
+	name, ok := watchedNames[id]
+	return name, ok
+}
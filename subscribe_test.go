@@ -0,0 +1,70 @@
+// Copyright © 2019 Weald Technology Trading
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wallet
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestDiffAccounts(t *testing.T) {
+	added := uuid.New()
+	removed := uuid.New()
+	modified := uuid.New()
+	unchanged := uuid.New()
+
+	prev := map[uuid.UUID]accountState{
+		removed:   {name: "removed", fingerprint: [32]byte{1}},
+		modified:  {name: "modified", fingerprint: [32]byte{2}},
+		unchanged: {name: "unchanged", fingerprint: [32]byte{3}},
+	}
+	curr := map[uuid.UUID]accountState{
+		added:     {name: "added", fingerprint: [32]byte{4}},
+		modified:  {name: "modified", fingerprint: [32]byte{5}},
+		unchanged: {name: "unchanged", fingerprint: [32]byte{3}},
+	}
+
+	events := diffAccounts("wallet1", prev, curr)
+
+	kinds := make(map[string]EventKind, len(events))
+	for _, ev := range events {
+		if ev.WalletName != "wallet1" {
+			t.Errorf("unexpected wallet name %q", ev.WalletName)
+		}
+		kinds[ev.AccountName] = ev.Kind
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d: %+v", len(events), events)
+	}
+	if kind, ok := kinds["added"]; !ok || kind != Added {
+		t.Errorf("expected an Added event for 'added', got %v (present=%v)", kind, ok)
+	}
+	if kind, ok := kinds["removed"]; !ok || kind != Removed {
+		t.Errorf("expected a Removed event for 'removed', got %v (present=%v)", kind, ok)
+	}
+	if kind, ok := kinds["modified"]; !ok || kind != Modified {
+		t.Errorf("expected a Modified event for 'modified' (fingerprint changed), got %v (present=%v)", kind, ok)
+	}
+	if _, ok := kinds["unchanged"]; ok {
+		t.Errorf("did not expect an event for 'unchanged'")
+	}
+}
+
+func TestDiffAccountsEmpty(t *testing.T) {
+	if events := diffAccounts("wallet1", nil, nil); len(events) != 0 {
+		t.Errorf("expected no events diffing two empty sets, got %+v", events)
+	}
+}
@@ -14,22 +14,32 @@
 package wallet
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/wealdtech/go-ecodec"
+	e2types "github.com/wealdtech/go-eth2-types/v2"
 	hd "github.com/wealdtech/go-eth2-wallet-hd"
 	nd "github.com/wealdtech/go-eth2-wallet-nd"
 	types "github.com/wealdtech/go-eth2-wallet-types"
+	distributed "github.com/wealdtech/go-eth2-wallet/distributed"
 )
 
 // walletOptions are the optons used when opening and creating wallets.
 type walletOptions struct {
-	store      types.Store
-	encryptor  types.Encryptor
-	walletType string
-	passphrase []byte
+	store             types.Store
+	encryptor         types.Encryptor
+	walletType        string
+	passphrase        []byte
+	peers             map[uint64]string
+	peerStores        map[uint64]types.Store
+	signingThreshold  uint32
+	walletName        string
+	accountNamePrefix string
+	watchInterval     time.Duration
 }
 
 // Option gives options to OpenWallet and CreateWallet.
@@ -71,6 +81,75 @@ func WithType(walletType string) Option {
 	})
 }
 
+// WithPeers sets the participant endpoints, keyed by participant ID, used when
+// splitting an account's private key into shares for a distributed wallet (see
+// ConvertWallet). A wallet is created without peers; they are per-account.
+func WithPeers(peers map[uint64]string) Option {
+	return optionFunc(func(o *walletOptions) {
+		o.peers = peers
+	})
+}
+
+// WithPeerStores sets the store to which each non-local participant's share is
+// written when splitting an account's private key into a distributed wallet
+// (see ConvertWallet). A participant with no entry here, including the local
+// one (whose share is persisted by CreateDistributedAccount itself), is skipped.
+func WithPeerStores(stores map[uint64]types.Store) Option {
+	return optionFunc(func(o *walletOptions) {
+		o.peerStores = stores
+	})
+}
+
+// WithSigningThreshold sets the number of partial signatures required to
+// reconstruct a group signature for accounts split into a distributed wallet
+// (see ConvertWallet).
+func WithSigningThreshold(threshold uint32) Option {
+	return optionFunc(func(o *walletOptions) {
+		o.signingThreshold = threshold
+	})
+}
+
+// WithWalletName sets the name of the wallet opened or created by ImportKeystore.
+func WithWalletName(name string) Option {
+	return optionFunc(func(o *walletOptions) {
+		o.walletName = name
+	})
+}
+
+// WithAccountNamePrefix sets the prefix used to name the accounts created by
+// ImportKeystore; accounts are named "prefix/0".."prefix/N-1".
+func WithAccountNamePrefix(prefix string) Option {
+	return optionFunc(func(o *walletOptions) {
+		o.accountNamePrefix = prefix
+	})
+}
+
+// WithWatchInterval sets the interval at which Subscribe's background poller
+// re-lists the store looking for added, removed or modified accounts.
+func WithWatchInterval(interval time.Duration) Option {
+	return optionFunc(func(o *walletOptions) {
+		o.watchInterval = interval
+	})
+}
+
+// DistributedAccount is an account held by a distributed wallet.
+// Sign() never fully materialises its private key locally: it gathers partial
+// signatures from the account's peers and reconstructs the group signature by
+// Lagrange interpolation once at least SigningThreshold() of them have responded.
+type DistributedAccount interface {
+	types.Account
+
+	// CompositePublicKey is the public key of the group, whose private key is
+	// split among the participants.
+	CompositePublicKey() e2types.PublicKey
+
+	// Participants is the set of peer endpoints holding a share of this account's key, keyed by participant ID.
+	Participants() map[uint64]string
+
+	// SigningThreshold is the minimum number of partial signatures required to reconstruct a group signature.
+	SigningThreshold() uint32
+}
+
 // ImportWallet imports a wallet from its encrypted export.
 func ImportWallet(encryptedData []byte, passphrase []byte) (types.Wallet, error) {
 	type walletExt struct {
@@ -94,6 +173,8 @@ func ImportWallet(encryptedData []byte, passphrase []byte) (types.Wallet, error)
 		wallet, err = nd.Import(encryptedData, passphrase, store, encryptor)
 	case "hd", "hierarchical deterministic":
 		wallet, err = hd.Import(encryptedData, passphrase, store, encryptor)
+	case "distributed":
+		wallet, err = distributed.Import(encryptedData, passphrase, store, encryptor)
 	default:
 		return nil, fmt.Errorf("unsupported wallet type %q", ext.Wallet.Type)
 	}
@@ -136,6 +217,8 @@ func CreateWallet(name string, opts ...Option) (types.Wallet, error) {
 		return nd.CreateWallet(name, options.store, options.encryptor)
 	case "hd", "hierarchical deterministic":
 		return hd.CreateWallet(name, options.passphrase, options.store, options.encryptor)
+	case "distributed":
+		return distributed.CreateWallet(name, options.store, options.encryptor)
 	default:
 		return nil, fmt.Errorf("unhandled wallet type %q", options.walletType)
 	}
@@ -149,16 +232,7 @@ type walletInfo struct {
 
 // Wallets provides information on the available wallets.
 func Wallets() <-chan types.Wallet {
-	ch := make(chan types.Wallet, 1024)
-	go func() {
-		for data := range store.RetrieveWallets() {
-			wallet, err := walletFromBytes(data)
-			if err == nil {
-				ch <- wallet
-			}
-		}
-		close(ch)
-	}()
+	ch, _ := WalletsContext(context.Background())
 	return ch
 }
 
@@ -174,6 +248,8 @@ func walletFromBytes(data []byte) (types.Wallet, error) {
 		wallet, err = nd.DeserializeWallet(data, store, encryptor)
 	case "hd", "hierarchical deterministic":
 		wallet, err = hd.DeserializeWallet(data, store, encryptor)
+	case "distributed":
+		wallet, err = distributed.DeserializeWallet(data, store, encryptor)
 	default:
 		return nil, fmt.Errorf("unsupported wallet type %q", info.Type)
 	}